@@ -1,8 +1,11 @@
 package main
 
 import (
+	"fmt"
+
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	"github.com/ipfs/kubo/core/commands"
+	"github.com/ipfs/kubo/core/commands/cmdenv"
 	"github.com/ipfs/kubo/core/node"
 	"github.com/luanet/lua-proto/proto"
 )
@@ -13,6 +16,9 @@ type IpTest struct {
 	Gateway bool   `json:"gateway"`
 }
 
+const maxBytesOptionName = "max-bytes"
+const serverOptionName = "server"
+
 var portForwardError = "Node port forwarding is not accessible. Please change your router's configuration and try again."
 var testCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
@@ -20,19 +26,67 @@ var testCmd = &cmds.Command{
 		ShortDescription: `
 Before join Luanet, your node have to pass several tests.
 Include port forwarding, internet speedtest and socket connection test.
+
+Results stream as they're produced, so '--enc=json' gives a live
+progress feed instead of a single result at the end. Ctrl-C aborts the
+test.
 `,
 	},
 	Arguments: []cmds.Argument{},
-	Options:   []cmds.Option{},
-	NoRemote:  true,
-	Extra:     commands.CreateCmdExtras(commands.SetDoesNotUseRepo(true), commands.SetDoesNotUseConfigAsInput(true)),
-	PreRun:    commands.DaemonRunning,
+	Options: []cmds.Option{
+		cmds.Int64Option(maxBytesOptionName, "Any value > 0 runs the speedtest in its low-traffic saving mode (useful on metered connections). The value is not enforced as a byte limit."),
+		cmds.IntOption(serverOptionName, "Index of the ranked speedtest server to use (0 = nearest)."),
+	},
+	NoRemote: true,
+	Extra:    commands.CreateCmdExtras(commands.SetDoesNotUseRepo(true), commands.SetDoesNotUseConfigAsInput(true)),
+	PreRun:   commands.DaemonRunning,
 	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
 		// socket connection test
 		//TODO
-		result := make(chan proto.TestResult)
-		go node.NodeTest(result)
-		_ = <-result
+		opts := node.TestOptions{}
+		if maxBytes, ok := req.Options[maxBytesOptionName].(int64); ok {
+			opts.MaxBytes = maxBytes
+		}
+		if server, ok := req.Options[serverOptionName].(int); ok {
+			opts.ServerIndex = server
+		}
+
+		for p := range node.NodeTest(req.Context, opts) {
+			if err := res.Emit(p); err != nil {
+				return err
+			}
+		}
 		return nil
 	},
+	Type: proto.TestProgress{},
+	Subcommands: map[string]*cmds.Command{
+		"status": testStatusCmd,
+	},
+}
+
+var testStatusCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show the last reachability test result.",
+		ShortDescription: `
+Reports the cached port-forwarding result kept by the daemon's
+ReachabilityMonitor, which re-tests automatically whenever libp2p
+reports a reachability or address change, instead of requiring a fresh
+'ipfs test' run.
+`,
+	},
+	Arguments: []cmds.Argument{},
+	Options:   []cmds.Option{},
+	Run: func(req *cmds.Request, res cmds.ResponseEmitter, env cmds.Environment) error {
+		nd, err := cmdenv.GetNode(env)
+		if err != nil {
+			return err
+		}
+
+		if nd.ReachabilityMonitor == nil {
+			return fmt.Errorf("reachability monitor is not running on this node")
+		}
+
+		return cmds.EmitOnce(res, nd.ReachabilityMonitor.Result())
+	},
+	Type: proto.TestResult{},
 }
@@ -2,6 +2,13 @@ package config
 
 // Tracks the configuration of the luanet's identity.
 type Luanet struct {
+	// Enabled gates whether core/node/luanet's fx.Option group is
+	// included when building the node at all. Off by default so
+	// embedders of kubo-as-a-library don't run the luanet control
+	// plane (join, heartbeat, reachability monitor, command dispatcher)
+	// unless they opt in. The package is compiled in either way.
+	Enabled bool
+
 	Domain      string
 	Node        string
 	Api         string
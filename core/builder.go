@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+
+	ic "github.com/libp2p/go-libp2p/core/crypto"
+	p2phost "github.com/libp2p/go-libp2p/core/host"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	"go.uber.org/fx"
+
+	"github.com/ipfs/kubo/core/node"
+	"github.com/ipfs/kubo/core/node/luanet"
+	"github.com/ipfs/kubo/repo"
+)
+
+// BuildCfg configures NewNode. This repo only carries the luanet slice
+// of kubo's real construction path - the full blockstore/routing/
+// bitswap/etc. fx groups live upstream and aren't part of this series -
+// so LuanetOption is the one option group it actually threads through.
+type BuildCfg struct {
+	Host     p2phost.Host
+	Repo     repo.Repo
+	Identity peer.ID
+	PrivKey  ic.PrivKey
+
+	// ConfigRoot is the repo's config directory on disk. luanet.Options
+	// needs it supplied as a bare string for its CertStore provider -
+	// there's nothing else in this graph fx could satisfy that
+	// dependency from.
+	ConfigRoot string
+
+	// LuanetOption is included in the node's fx.App when set. Callers
+	// build it with luanet.Options, which itself returns a no-op
+	// fx.Options() when luanet is disabled, so it's safe to pass
+	// through unconditionally:
+	//
+	//	cfg, _ := r.Config()
+	//	core.BuildCfg{..., LuanetOption: luanet.Options(&cfg.Luanet)}
+	LuanetOption fx.Option
+}
+
+// populateParams pulls whatever the LuanetOption group provided back
+// out into the IpfsNode. Both fields are optional so this still works
+// when LuanetOption is luanet's disabled no-op group.
+type populateParams struct {
+	fx.In
+
+	Client  *luanet.Client            `optional:"true"`
+	Monitor *node.ReachabilityMonitor `optional:"true"`
+}
+
+// NewNode builds the luanet fields of an IpfsNode by running the
+// fx.App assembled from cfg and populating them the same way every
+// other optional IpfsNode field is populated: via a struct tagged
+// optional:"true".
+func NewNode(ctx context.Context, cfg BuildCfg) (*IpfsNode, error) {
+	n := &IpfsNode{
+		Identity:   cfg.Identity,
+		PrivateKey: cfg.PrivKey,
+		PeerHost:   cfg.Host,
+		Repo:       cfg.Repo,
+		ConfigRoot: cfg.ConfigRoot,
+		ctx:        ctx,
+		stop:       func() error { return nil },
+	}
+
+	opt := cfg.LuanetOption
+	if opt == nil {
+		opt = fx.Options()
+	}
+
+	app := fx.New(
+		fx.Supply(
+			fx.Annotate(cfg.Host, fx.As(new(p2phost.Host))),
+			fx.Annotate(cfg.Repo, fx.As(new(repo.Repo))),
+			fx.Annotate(cfg.PrivKey, fx.As(new(ic.PrivKey))),
+			cfg.Identity,
+			cfg.ConfigRoot,
+		),
+		opt,
+		fx.Invoke(func(p populateParams) {
+			n.LuanetClient = p.Client
+			n.ReachabilityMonitor = p.Monitor
+		}),
+	)
+	if err := app.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	n.stop = func() error { return app.Stop(context.Background()) }
+	return n, nil
+}
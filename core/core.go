@@ -11,16 +11,9 @@ package core
 
 import (
 	"context"
-	"crypto/tls"
-	"encoding/gob"
 	"encoding/json"
-	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
 	"time"
 
 	"github.com/ipfs/go-filestore"
@@ -58,6 +51,7 @@ import (
 	"github.com/ipfs/kubo/core/bootstrap"
 	"github.com/ipfs/kubo/core/node"
 	"github.com/ipfs/kubo/core/node/libp2p"
+	"github.com/ipfs/kubo/core/node/luanet"
 	"github.com/ipfs/kubo/fuse/mount"
 	"github.com/ipfs/kubo/p2p"
 	"github.com/ipfs/kubo/peering"
@@ -65,7 +59,6 @@ import (
 	irouting "github.com/ipfs/kubo/routing"
 
 	"github.com/luanet/lua-proto/proto"
-	"github.com/lucas-clemente/quic-go"
 )
 
 var log = logging.Logger("core")
@@ -76,8 +69,14 @@ type IpfsNode struct {
 	// Self
 	Identity peer.ID // the local node's identity
 
-	// Quic connection to luanet
-	Stream *quic.Stream `optional:"true"`
+	// LuanetClient talks to the luanet coordinator over the
+	// /luanet/control libp2p protocol.
+	LuanetClient *luanet.Client `optional:"true"`
+
+	// ReachabilityMonitor tracks NAT/port-forwarding status as libp2p
+	// identify events arrive, instead of only on a human-triggered
+	// `ipfs test`.
+	ReachabilityMonitor *node.ReachabilityMonitor `optional:"true"`
 
 	Repo repo.Repo
 
@@ -196,103 +195,6 @@ func (n *IpfsNode) loadBootstrapPeers() ([]peer.AddrInfo, error) {
 	return cfg.BootstrapPeers()
 }
 
-func (n *IpfsNode) JoinLuanet() (*proto.JoinRes, error) {
-	cfg, err := n.Repo.Config()
-	if err != nil {
-		return nil, err
-	}
-
-	gob.Register(proto.Ip{})
-	gob.Register(proto.JoinReq{})
-	gob.Register(proto.JoinRes{})
-	tlsConf := &tls.Config{
-		InsecureSkipVerify: true,
-		NextProtos:         []string{"wq-vvv-01"},
-	}
-
-	log.Info("Connecting to node address: ", cfg.Luanet.Api)
-	conn, err := quic.DialAddr(cfg.Luanet.Api, tlsConf, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	stream, err := conn.OpenStreamSync(context.Background())
-	if err != nil {
-		return nil, err
-	}
-
-	expires := time.Now().Unix() + cfg.Luanet.ExpiresTime
-	bytes := []byte(n.Identity.String() + "." + strconv.FormatInt(expires, 10))
-	signature, err := n.PrivateKey.Sign(bytes)
-	if err != nil {
-		return nil, err
-	}
-
-	ip4 := n.GetIpInfo("ip4")
-	ip6 := n.GetIpInfo("ip6")
-	message := proto.Proto{
-		Service: proto.JoinService,
-		Data: proto.JoinReq{
-			Address:   n.Identity.String(),
-			Ipv4:      *ip4,
-			Ipv6:      *ip6,
-			Signature: signature,
-			Expires:   expires,
-		},
-	}
-
-	n.Stream = &stream
-	n.SendQuicMsg(message)
-
-	msg := n.ReadQuicMsg()
-	joinRes := msg.Data.(proto.JoinRes)
-	if !joinRes.Success {
-		return nil, fmt.Errorf("Failed to join lua network: %s", joinRes.Message)
-	}
-
-	// write certs to file
-	for ip, cert := range joinRes.Certs {
-		if err := os.MkdirAll(filepath.Join(n.ConfigRoot, "certs", ip), os.ModePerm); err != nil {
-			return nil, err
-		}
-
-		if err = ioutil.WriteFile(filepath.Join(n.ConfigRoot, "certs", ip, "private.pem"), []byte(cert.Pems.Privkey), os.ModePerm); err != nil {
-			return nil, err
-		}
-
-		_ = ioutil.WriteFile(filepath.Join(n.ConfigRoot, "certs", ip, "cert.pem"), []byte(cert.Pems.Cert), os.ModePerm)
-	}
-
-	return &joinRes, nil
-}
-
-func (n *IpfsNode) CmdHandlers() error {
-	ticker := time.NewTicker(250 * time.Millisecond)
-	for {
-		select {
-		case <-ticker.C:
-			msg := n.ReadQuicMsg()
-			message := proto.Proto{
-				Service: msg.Service,
-			}
-
-			switch msg.Service {
-			case proto.SpeedTestService:
-				// result := make(chan proto.TestResult)
-				// go node.NodeTest(result)
-				// message.Data = <-result
-			}
-
-			if message.Data != nil {
-				fmt.Println("Sending cmd response....")
-				n.SendQuicMsg(message)
-			}
-		}
-	}
-
-	return nil
-}
-
 func (n *IpfsNode) GetIpInfo(version string) *proto.Ip {
 	var ip proto.Ip = proto.Ip{}
 	cfg, err := n.Repo.Config()
@@ -311,55 +213,6 @@ func (n *IpfsNode) GetIpInfo(version string) *proto.Ip {
 	return &ip
 }
 
-func (n *IpfsNode) HeartBeat() {
-	gob.Register(proto.HeartBeatReq{})
-	gob.Register(proto.HeartBeatRes{})
-	gob.Register(proto.Stats{})
-	gob.Register(proto.TestResult{})
-	gob.Register(proto.IpTest{})
-	ticker := time.NewTicker(time.Second)
-	for {
-		select {
-		case <-ticker.C:
-			// TODO get stats from gateway only, not IPFS
-			totals := n.Reporter.GetBandwidthTotals()
-			message := proto.Proto{
-				Service: proto.HeartBeatService,
-				Data: proto.HeartBeatReq{
-					Stats: proto.Stats{
-						Storage: 0,
-						In:      totals.TotalIn,
-						Out:     totals.TotalOut,
-						Ingress: totals.RateIn,
-						Egress:  totals.RateOut,
-					},
-				},
-			}
-
-			n.SendQuicMsg(message)
-		}
-	}
-}
-
-func (n *IpfsNode) SendQuicMsg(msg proto.Proto) {
-	enc := gob.NewEncoder(*n.Stream) // Will write to network.
-	err := enc.Encode(msg)
-	if err != nil {
-		log.Error("Failed to send quic message: %v", err)
-		n.JoinLuanet()
-	}
-}
-
-func (n *IpfsNode) ReadQuicMsg() (message proto.Proto) {
-	dec := gob.NewDecoder(*n.Stream)
-	err := dec.Decode(&message)
-	if err != nil {
-		log.Error("Failed to read quic message: %v", err)
-	}
-
-	return
-}
-
 type ConstructPeerHostOpts struct {
 	AddrsFactory      p2pbhost.AddrsFactory
 	DisableNatPortMap bool
@@ -0,0 +1,40 @@
+package luanet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/luanet/lua-proto/proto"
+)
+
+// CertStore persists the TLS certs the coordinator issues during Join
+// under <configRoot>/certs/<ip>. It's split out from Client so tests
+// can inject a mock control-plane (and a stub CertStore) without
+// touching the real config directory.
+type CertStore struct {
+	configRoot string
+}
+
+// NewCertStore returns a CertStore rooted at configRoot.
+func NewCertStore(configRoot string) *CertStore {
+	return &CertStore{configRoot: configRoot}
+}
+
+// Save writes each cert's key pair to <configRoot>/certs/<ip>/{private,cert}.pem.
+func (s *CertStore) Save(certs map[string]*proto.Cert) error {
+	for ip, cert := range certs {
+		dir := filepath.Join(s.configRoot, "certs", ip)
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return fmt.Errorf("creating cert dir for %s: %w", ip, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "private.pem"), []byte(cert.Pems.Privkey), os.ModePerm); err != nil {
+			return fmt.Errorf("writing private key for %s: %w", ip, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "cert.pem"), []byte(cert.Pems.Cert), os.ModePerm); err != nil {
+			return fmt.Errorf("writing cert for %s: %w", ip, err)
+		}
+	}
+	return nil
+}
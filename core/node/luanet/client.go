@@ -0,0 +1,125 @@
+package luanet
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	ic "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/luanet/lua-proto/proto"
+)
+
+// Client is a stateless helper for talking to the luanet coordinator
+// over the control protocol. It holds no live connection: every call
+// opens a stream on demand and closes it when done, leaving libp2p's
+// own connection manager and swarm dialing to handle reconnection.
+type Client struct {
+	host     host.Host
+	identity peer.ID
+	privKey  ic.PrivKey
+	cfg      *config.Luanet
+}
+
+// NewClient builds a Client bound to h for the given identity and
+// luanet configuration.
+func NewClient(h host.Host, identity peer.ID, privKey ic.PrivKey, cfg *config.Luanet) *Client {
+	return &Client{host: h, identity: identity, privKey: privKey, cfg: cfg}
+}
+
+// coordinator resolves the configured coordinator address into a
+// dialable peer.AddrInfo. cfg.Api is a multiaddr with a trailing
+// /p2p/<peer id> component, e.g.
+// "/dns4/api.luanet.io/tcp/4001/p2p/QmCoordinator...".
+func (c *Client) coordinator() (peer.AddrInfo, error) {
+	addr, err := ma.NewMultiaddr(c.cfg.Api)
+	if err != nil {
+		return peer.AddrInfo{}, fmt.Errorf("invalid luanet.Api address %q: %w", c.cfg.Api, err)
+	}
+	info, err := peer.AddrInfoFromP2pAddr(addr)
+	if err != nil {
+		return peer.AddrInfo{}, fmt.Errorf("luanet.Api address %q is missing a /p2p peer id: %w", c.cfg.Api, err)
+	}
+	return *info, nil
+}
+
+// Send opens a stream to the coordinator, writes req, reads back the
+// matching response, and closes the stream.
+func (c *Client) Send(ctx context.Context, req *proto.Proto) (*proto.Proto, error) {
+	coord, err := c.coordinator()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.host.Connect(ctx, coord); err != nil {
+		return nil, fmt.Errorf("connecting to luanet coordinator: %w", err)
+	}
+
+	s, err := c.host.NewStream(ctx, coord.ID, ProtocolID)
+	if err != nil {
+		return nil, fmt.Errorf("opening luanet control stream: %w", err)
+	}
+	defer s.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = s.SetDeadline(deadline)
+	}
+
+	if err := writeMsg(s, req); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("sending luanet control message: %w", err)
+	}
+
+	res := &proto.Proto{}
+	if err := readMsg(s, res); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("reading luanet control response: %w", err)
+	}
+	return res, nil
+}
+
+// Join registers the local node with the luanet coordinator and
+// persists any TLS certs it returns via certs.
+func (c *Client) Join(ctx context.Context, certs *CertStore, ipv4, ipv6 *proto.Ip) (*proto.JoinRes, error) {
+	expires := time.Now().Unix() + c.cfg.ExpiresTime
+	payload := []byte(c.identity.String() + "." + strconv.FormatInt(expires, 10))
+	signature, err := c.privKey.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &proto.Proto{
+		Service: proto.JoinService,
+		Data: &proto.JoinReq{
+			Address:   c.identity.String(),
+			Ipv4:      *ipv4,
+			Ipv6:      *ipv6,
+			Signature: signature,
+			Expires:   expires,
+		},
+	}
+
+	res, err := c.Send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	joinRes, ok := res.Data.(*proto.JoinRes)
+	if !ok {
+		return nil, fmt.Errorf("unexpected luanet join response type %T", res.Data)
+	}
+	if !joinRes.Success {
+		return nil, fmt.Errorf("failed to join lua network: %s", joinRes.Message)
+	}
+
+	if err := certs.Save(joinRes.Certs); err != nil {
+		return nil, err
+	}
+
+	return joinRes, nil
+}
@@ -0,0 +1,187 @@
+package luanet
+
+import (
+	"context"
+	"time"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/metrics"
+	"go.uber.org/fx"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/repo"
+	"github.com/luanet/lua-proto/proto"
+)
+
+// Options returns the fx.Option group that wires the luanet control
+// plane - client, reachability monitor, heartbeat loop, and command
+// dispatcher - into core/node, gated by cfg.Enabled. This mirrors how
+// kubo's own core/node packages bundle libp2p/routing/host construction
+// into fx.Option groups: callers (Standard vs Offline-style
+// constructors) just decide whether this group is included at all,
+// rather than the luanet services being started for every IpfsNode.
+//
+// Callers hand the result to core.NewNode as BuildCfg.LuanetOption;
+// NewNode populates IpfsNode.LuanetClient and
+// IpfsNode.ReachabilityMonitor from whatever this group provides.
+func Options(cfg *config.Luanet) fx.Option {
+	if cfg == nil || !cfg.Enabled {
+		return fx.Options()
+	}
+
+	return fx.Options(
+		fx.Provide(func(r repo.Repo) (*config.Luanet, error) {
+			full, err := r.Config()
+			if err != nil {
+				return nil, err
+			}
+			return &full.Luanet, nil
+		}),
+		fx.Provide(NewClient),
+		fx.Provide(func(configRoot string) *CertStore { return NewCertStore(configRoot) }),
+		fx.Provide(provideReachabilityMonitor),
+		fx.Provide(provideServiceRegistry),
+		fx.Invoke(JoinClient),
+		fx.Invoke(HeartbeatLoop),
+		fx.Invoke(StartReachabilityMonitor),
+		fx.Invoke(CmdDispatcher),
+	)
+}
+
+type joinClientParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Client    *Client
+	Certs     *CertStore
+	Cfg       *config.Luanet
+}
+
+// joinTimeout bounds a single Join attempt. Without it, a coordinator
+// that accepts the stream but never replies would hang the join
+// closure forever, and the Supervisor's backoff never gets the chance
+// to kick in because the closure never returns.
+const joinTimeout = 30 * time.Second
+
+// JoinClient keeps the node joined to luanet for the lifetime of the
+// fx app, using a Supervisor to retry with backoff instead of the old
+// recursive reconnect-on-send-failure.
+func JoinClient(p joinClientParams) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go NewSupervisor(func(ctx context.Context) error {
+				joinCtx, cancel := context.WithTimeout(ctx, joinTimeout)
+				defer cancel()
+
+				ip4, ip6 := DetectAddresses(p.Cfg.Domain)
+				_, err := p.Client.Join(joinCtx, p.Certs, ip4, ip6)
+				return err
+			}).Run(ctx)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
+
+type heartbeatParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Client    *Client
+	Repo      repo.Repo
+	Reporter  *metrics.BandwidthCounter `optional:"true"`
+}
+
+// HeartbeatLoop sends periodic bandwidth and storage stats to the
+// luanet coordinator for the lifetime of the fx app.
+func HeartbeatLoop(p heartbeatParams) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			go runHeartbeat(ctx, p.Client, p.Repo, p.Reporter)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
+
+// heartbeatSendTimeout bounds a single heartbeat Send. runHeartbeat is
+// a single goroutine awaiting each Send before it goes back to
+// ticker.C, so a coordinator that never replies would otherwise wedge
+// the heartbeat loop permanently instead of just missing a beat.
+const heartbeatSendTimeout = 10 * time.Second
+
+func runHeartbeat(ctx context.Context, client *Client, r repo.Repo, reporter *metrics.BandwidthCounter) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var totals metrics.Stats
+			if reporter != nil {
+				totals = reporter.GetBandwidthTotals()
+			}
+
+			storage, err := r.GetStorageUsage(ctx)
+			if err != nil {
+				log.Warnf("failed to read repo storage usage for luanet heartbeat: %s", err)
+			}
+
+			message := &proto.Proto{
+				Service: proto.HeartBeatService,
+				Data: &proto.HeartBeatReq{
+					Stats: proto.Stats{
+						Storage: storage,
+						In:      totals.TotalIn,
+						Out:     totals.TotalOut,
+						Ingress: totals.RateIn,
+						Egress:  totals.RateOut,
+					},
+				},
+			}
+
+			sendCtx, cancel := context.WithTimeout(ctx, heartbeatSendTimeout)
+			_, err = client.Send(sendCtx, message)
+			cancel()
+			if err != nil {
+				log.Warnf("failed to send luanet heartbeat: %s", err)
+			}
+		}
+	}
+}
+
+type cmdDispatcherParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Host      host.Host
+	Registry  *ServiceRegistry
+}
+
+// CmdDispatcher serves inbound control-channel streams for the
+// lifetime of the fx app, dispatching through Registry.
+func CmdDispatcher(p cmdDispatcherParams) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			Serve(ctx, p.Host, p.Registry.Dispatch)
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			cancel()
+			return nil
+		},
+	})
+}
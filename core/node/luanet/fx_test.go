@@ -0,0 +1,86 @@
+package luanet
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p"
+	ic "github.com/libp2p/go-libp2p/core/crypto"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"go.uber.org/fx"
+
+	"github.com/ipfs/kubo/config"
+	"github.com/ipfs/kubo/repo"
+)
+
+// nodeDeps supplies everything core.NewNode puts into the graph apart
+// from configRoot (host, repo, private key, peer id), so the subtests
+// below differ only in whether configRoot is supplied.
+func nodeDeps(t *testing.T) fx.Option {
+	t.Helper()
+	priv, _, err := ic.GenerateEd25519Key(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := libp2p.New(libp2p.Identity(priv), libp2p.NoListenAddrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+
+	return fx.Supply(
+		fx.Annotate(h, fx.As(new(host.Host))),
+		fx.Annotate(&repo.Mock{}, fx.As(new(repo.Repo))),
+		fx.Annotate(priv, fx.As(new(ic.PrivKey))),
+		id,
+	)
+}
+
+// TestOptionsCertStoreNeedsConfigRootSupplied pins down the contract
+// Options relies on but can't enforce itself: its CertStore provider
+// wants a bare string for configRoot, and fx has no other candidate to
+// satisfy that with. Callers (core.NewNode) must fx.Supply one
+// themselves. This is the exact wiring gap that let Luanet.Enabled
+// nodes fail fx.App.Start in production while compiling fine.
+func TestOptionsCertStoreNeedsConfigRootSupplied(t *testing.T) {
+	cfg := &config.Luanet{Enabled: true}
+
+	t.Run("fails to start without a configRoot string supplied", func(t *testing.T) {
+		app := fx.New(
+			nodeDeps(t),
+			Options(cfg),
+			fx.Invoke(func(*CertStore) {}),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := app.Start(ctx); err == nil {
+			t.Fatal("expected Start to fail: nothing supplies the configRoot string CertStore's provider needs")
+		}
+	})
+
+	t.Run("starts once the caller supplies configRoot", func(t *testing.T) {
+		app := fx.New(
+			nodeDeps(t),
+			fx.Supply("/tmp/luanet-test-config-root"),
+			Options(cfg),
+			fx.Invoke(func(*CertStore) {}),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := app.Start(ctx); err != nil {
+			t.Fatalf("expected Start to succeed once configRoot is supplied, got: %s", err)
+		}
+		if err := app.Stop(ctx); err != nil {
+			t.Fatalf("Stop: %s", err)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package luanet
+
+import (
+	"context"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+
+	"github.com/luanet/lua-proto/proto"
+)
+
+// Handler answers one control-channel request. Returning a nil response
+// and a nil error means "no reply": Serve closes the stream without
+// writing anything. ServiceRegistry does not drop requests for services
+// nobody registered a handler for silently; Dispatch returns an error
+// for them, and Serve logs it and resets the stream.
+type Handler func(ctx context.Context, req *proto.Proto) (*proto.Proto, error)
+
+// Serve registers handler as the receiver for ProtocolID on h. Each
+// inbound stream carries exactly one request/response exchange -
+// libp2p's stream multiplexing is what lets heartbeat, test, and cmd
+// traffic run concurrently instead of all serializing through a single
+// socket the way the old QUIC stream did.
+//
+// ctx bounds every in-flight handler call: when it's canceled (node
+// shutdown), handlers blocked on it return rather than leaking past the
+// node's own lifetime.
+func Serve(ctx context.Context, h host.Host, handler Handler) {
+	h.SetStreamHandler(ProtocolID, func(s network.Stream) {
+		defer s.Close()
+
+		req := &proto.Proto{}
+		if err := readMsg(s, req); err != nil {
+			log.Warnf("luanet control: failed to read request from %s: %s", s.Conn().RemotePeer(), err)
+			s.Reset()
+			return
+		}
+
+		res, err := handler(ctx, req)
+		if err != nil {
+			log.Warnf("luanet control: handler error for service %v: %s", req.Service, err)
+			s.Reset()
+			return
+		}
+		if res == nil {
+			return
+		}
+
+		if err := writeMsg(s, res); err != nil {
+			log.Warnf("luanet control: failed to write response to %s: %s", s.Conn().RemotePeer(), err)
+		}
+	})
+}
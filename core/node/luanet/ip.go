@@ -0,0 +1,30 @@
+package luanet
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/luanet/lua-proto/proto"
+)
+
+// DetectAddresses probes http://ip4.<domain> and http://ip6.<domain>
+// the same way the daemon's port-forwarding tests do, returning the
+// addresses the coordinator should register during Join.
+func DetectAddresses(domain string) (ipv4, ipv6 *proto.Ip) {
+	return detectIP(domain, "ip4"), detectIP(domain, "ip6")
+}
+
+func detectIP(domain, version string) *proto.Ip {
+	ip := &proto.Ip{}
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	r, err := client.Get("http://" + version + "." + domain)
+	if err != nil {
+		return ip
+	}
+	defer r.Body.Close()
+
+	_ = json.NewDecoder(r.Body).Decode(ip)
+	return ip
+}
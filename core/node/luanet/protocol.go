@@ -0,0 +1,74 @@
+// Package luanet implements the luanet control channel as a libp2p
+// protocol. It replaces the previous raw QUIC socket (dialed with
+// InsecureSkipVerify) with a length-prefixed message framed over a
+// libp2p stream, mirroring how kubo itself moved the gateway from a
+// bespoke HTTP listener onto libp2p streams.
+//
+// Running over libp2p gives the control channel peer-id authentication,
+// automatic reconnection and NAT traversal via the swarm, and cheap
+// stream multiplexing: heartbeat, test, and cmd traffic each get their
+// own stream instead of fighting over one socket.
+//
+// The wire encoding is still encoding/gob, not protobuf: proto.Proto's
+// Data field is a bare interface{}, not a generated oneof, so there's
+// no protoreflect descriptor for it to marshal against - real protobuf
+// marshaling needs lua-proto itself to grow Data as a oneof (or wrap it
+// in a google.protobuf.Any) before this channel can speak it. gob
+// already handles a registered interface field correctly, which is the
+// property this channel actually needs.
+package luanet
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	logging "github.com/ipfs/go-log"
+	"github.com/libp2p/go-libp2p/core/network"
+	"github.com/libp2p/go-libp2p/core/protocol"
+	msgio "github.com/libp2p/go-msgio"
+
+	"github.com/luanet/lua-proto/proto"
+)
+
+var log = logging.Logger("luanet")
+
+// ProtocolID is the libp2p protocol the luanet control channel speaks.
+// The version suffix lets us land breaking wire changes as new
+// protocol IDs while old nodes keep talking to old coordinators.
+const ProtocolID = protocol.ID("/luanet/control/1.0.0")
+
+// maxMessageSize bounds a single control-channel frame. Every message
+// this protocol carries (join, heartbeat, cmd request/response) is
+// small; the limit exists to stop a misbehaving peer from making us
+// buffer an unbounded read.
+const maxMessageSize = 4 << 20 // 4MiB
+
+func init() {
+	// Every concrete type that can show up in proto.Proto.Data must be
+	// registered so gob can encode/decode it through that interface
+	// field.
+	gob.Register(&proto.JoinReq{})
+	gob.Register(&proto.JoinRes{})
+	gob.Register(&proto.HeartBeatReq{})
+	gob.Register(&proto.HeartBeatRes{})
+	gob.Register(&proto.Stats{})
+	gob.Register(&proto.TestResult{})
+}
+
+// writeMsg gob-encodes msg and writes it length-prefixed to s.
+func writeMsg(s network.Stream, msg *proto.Proto) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(msg); err != nil {
+		return err
+	}
+	return msgio.NewVarintWriter(s).WriteMsg(buf.Bytes())
+}
+
+// readMsg reads a length-prefixed gob-encoded message from s into msg.
+func readMsg(s network.Stream, msg *proto.Proto) error {
+	data, err := msgio.NewVarintReaderSize(s, maxMessageSize).ReadMsg()
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(msg)
+}
@@ -0,0 +1,69 @@
+package luanet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/luanet/lua-proto/proto"
+)
+
+// defaultMaxConcurrentHandlers bounds how many registered handlers can
+// run at once. It exists so one slow handler (a speedtest can take
+// tens of seconds) can't stall every other service sharing the control
+// channel - but it's still small enough that a burst of cmd traffic
+// can't pile up unbounded goroutines.
+const defaultMaxConcurrentHandlers = 8
+
+// ServiceRegistry dispatches inbound control-channel requests to a
+// Handler registered per proto.ServiceID. It replaces the single
+// hard-coded switch the old CmdHandlers loop had for
+// proto.SpeedTestService.
+type ServiceRegistry struct {
+	handlers map[proto.ServiceID]Handler
+	sem      chan struct{}
+}
+
+// NewServiceRegistry returns an empty registry that runs at most
+// maxConcurrent handlers at a time. A value <= 0 uses
+// defaultMaxConcurrentHandlers.
+func NewServiceRegistry(maxConcurrent int) *ServiceRegistry {
+	if maxConcurrent <= 0 {
+		maxConcurrent = defaultMaxConcurrentHandlers
+	}
+	return &ServiceRegistry{
+		handlers: make(map[proto.ServiceID]Handler),
+		sem:      make(chan struct{}, maxConcurrent),
+	}
+}
+
+// Register adds (or replaces) the handler for service.
+func (r *ServiceRegistry) Register(service proto.ServiceID, handler Handler) {
+	r.handlers[service] = handler
+}
+
+// Dispatch looks up the handler registered for req.Service and runs it
+// inside the bounded worker pool, recovering from any panic so a
+// misbehaving handler can't take the stream-handling goroutine down
+// with it. It has the Handler signature so it can be passed straight to
+// Serve.
+func (r *ServiceRegistry) Dispatch(ctx context.Context, req *proto.Proto) (res *proto.Proto, err error) {
+	handler, ok := r.handlers[req.Service]
+	if !ok {
+		return nil, fmt.Errorf("no handler registered for service %v", req.Service)
+	}
+
+	select {
+	case r.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-r.sem }()
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler for service %v panicked: %v", req.Service, p)
+		}
+	}()
+
+	return handler(ctx, req)
+}
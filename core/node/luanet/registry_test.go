@@ -0,0 +1,115 @@
+package luanet
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luanet/lua-proto/proto"
+)
+
+func TestServiceRegistryDispatchesToRegisteredHandler(t *testing.T) {
+	registry := NewServiceRegistry(0)
+	registry.Register(proto.RepoStatService, func(ctx context.Context, req *proto.Proto) (*proto.Proto, error) {
+		return &proto.Proto{Service: proto.RepoStatService}, nil
+	})
+
+	res, err := registry.Dispatch(context.Background(), &proto.Proto{Service: proto.RepoStatService})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if res.Service != proto.RepoStatService {
+		t.Fatalf("expected a RepoStatService response, got %v", res.Service)
+	}
+}
+
+func TestServiceRegistryDispatchUnregisteredServiceErrors(t *testing.T) {
+	registry := NewServiceRegistry(0)
+
+	if _, err := registry.Dispatch(context.Background(), &proto.Proto{Service: proto.RepoStatService}); err == nil {
+		t.Fatal("expected an error for a service with no registered handler")
+	}
+}
+
+func TestServiceRegistryDispatchRecoversHandlerPanic(t *testing.T) {
+	registry := NewServiceRegistry(0)
+	registry.Register(proto.RepoStatService, func(ctx context.Context, req *proto.Proto) (*proto.Proto, error) {
+		panic("boom")
+	})
+
+	if _, err := registry.Dispatch(context.Background(), &proto.Proto{Service: proto.RepoStatService}); err == nil {
+		t.Fatal("expected a panicking handler to surface as an error")
+	}
+}
+
+func TestServiceRegistryDispatchBoundsConcurrency(t *testing.T) {
+	const maxConcurrent = 2
+
+	registry := NewServiceRegistry(maxConcurrent)
+
+	var (
+		mu      sync.Mutex
+		current int
+		peak    int
+	)
+	release := make(chan struct{})
+	registry.Register(proto.RepoStatService, func(ctx context.Context, req *proto.Proto) (*proto.Proto, error) {
+		mu.Lock()
+		current++
+		if current > peak {
+			peak = current
+		}
+		mu.Unlock()
+
+		<-release
+
+		mu.Lock()
+		current--
+		mu.Unlock()
+		return &proto.Proto{}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < maxConcurrent*2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.Dispatch(context.Background(), &proto.Proto{Service: proto.RepoStatService})
+		}()
+	}
+
+	// Give the handlers time to pile up against the semaphore before
+	// releasing them.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if peak > maxConcurrent {
+		t.Fatalf("expected at most %d concurrent handlers, saw %d", maxConcurrent, peak)
+	}
+}
+
+func TestServiceRegistryDispatchReturnsOnContextCancel(t *testing.T) {
+	registry := NewServiceRegistry(1)
+	release := make(chan struct{})
+	registry.Register(proto.RepoStatService, func(ctx context.Context, req *proto.Proto) (*proto.Proto, error) {
+		<-release
+		return &proto.Proto{}, nil
+	})
+
+	// Saturate the single slot so the next Dispatch has to wait on ctx.
+	go registry.Dispatch(context.Background(), &proto.Proto{Service: proto.RepoStatService})
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := registry.Dispatch(ctx, &proto.Proto{Service: proto.RepoStatService})
+	close(release)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package luanet
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libp2p/go-libp2p/core/host"
+	"go.uber.org/fx"
+
+	"github.com/ipfs/kubo/core/node"
+	"github.com/ipfs/kubo/repo"
+	"github.com/luanet/lua-proto/proto"
+)
+
+type registryParams struct {
+	fx.In
+
+	Repo    repo.Repo
+	Monitor *node.ReachabilityMonitor
+}
+
+// provideServiceRegistry builds the ServiceRegistry CmdDispatcher
+// serves, with the built-in handlers (speedtest, reachability, repo
+// stat) already registered.
+func provideServiceRegistry(p registryParams) *ServiceRegistry {
+	registry := NewServiceRegistry(0)
+	registry.Register(proto.SpeedTestService, handleSpeedTest)
+	registry.Register(proto.ReachabilityService, handleReachability(p.Monitor))
+	registry.Register(proto.RepoStatService, handleRepoStat(p.Repo))
+	return registry
+}
+
+// handleSpeedTest runs node.NodeTest with default options and replies
+// with its final TestResult. Canceling ctx aborts the test.
+func handleSpeedTest(ctx context.Context, req *proto.Proto) (*proto.Proto, error) {
+	for p := range node.NodeTest(ctx, node.TestOptions{}) {
+		if p.Phase == proto.TestPhaseFinal && p.Result != nil {
+			return &proto.Proto{Service: proto.SpeedTestService, Data: p.Result}, nil
+		}
+	}
+	return nil, ctx.Err()
+}
+
+func handleReachability(monitor *node.ReachabilityMonitor) Handler {
+	return func(ctx context.Context, req *proto.Proto) (*proto.Proto, error) {
+		if monitor == nil {
+			return nil, fmt.Errorf("reachability monitor is not running on this node")
+		}
+
+		result := monitor.Result()
+		return &proto.Proto{Service: proto.ReachabilityService, Data: &result}, nil
+	}
+}
+
+func handleRepoStat(r repo.Repo) Handler {
+	return func(ctx context.Context, req *proto.Proto) (*proto.Proto, error) {
+		usage, err := r.GetStorageUsage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reading repo storage usage: %w", err)
+		}
+
+		return &proto.Proto{
+			Service: proto.RepoStatService,
+			Data:    &proto.Stats{Storage: usage},
+		}, nil
+	}
+}
+
+type reachabilityParams struct {
+	fx.In
+
+	Host   host.Host
+	Client *Client `optional:"true"`
+}
+
+// provideReachabilityMonitor builds the ReachabilityMonitor CmdDispatcher's
+// ReachabilityService handler reads from, wiring its PushUpdate to the
+// luanet coordinator when a Client is available.
+func provideReachabilityMonitor(p reachabilityParams) *node.ReachabilityMonitor {
+	m := node.NewReachabilityMonitor(p.Host)
+	if p.Client != nil {
+		m.PushUpdate = func(result proto.TestResult) {
+			message := &proto.Proto{
+				Service: proto.HeartBeatService,
+				Data:    &proto.HeartBeatReq{Test: result},
+			}
+			if _, err := p.Client.Send(context.Background(), message); err != nil {
+				log.Warnf("failed to push luanet reachability update: %s", err)
+			}
+		}
+	}
+	return m
+}
+
+type startReachabilityParams struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Monitor   *node.ReachabilityMonitor
+}
+
+// StartReachabilityMonitor begins watching libp2p reachability events
+// for the lifetime of the fx app.
+func StartReachabilityMonitor(p startReachabilityParams) {
+	p.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			return p.Monitor.Start(context.Background())
+		},
+		OnStop: func(context.Context) error {
+			p.Monitor.Close()
+			return nil
+		},
+	})
+}
@@ -0,0 +1,61 @@
+package luanet
+
+import (
+	"context"
+	"time"
+)
+
+// Supervisor keeps the node joined to luanet, retrying with exponential
+// backoff when join fails. This centralizes reconnection in one place:
+// the old SendQuicMsg called JoinLuanet directly on every encode error,
+// which could spawn one reconnect attempt per failed send under a flaky
+// network and leak goroutines.
+type Supervisor struct {
+	join func(ctx context.Context) error
+}
+
+// NewSupervisor returns a Supervisor that calls join to (re)establish
+// luanet membership.
+func NewSupervisor(join func(ctx context.Context) error) *Supervisor {
+	return &Supervisor{join: join}
+}
+
+// Run calls join, then keeps retrying on failure with exponential
+// backoff (capped at maxBackoff) until ctx is done. Once join succeeds,
+// it waits keepAlive before re-joining, since a successful join means
+// the node is already a member - re-running join every minBackoff
+// would otherwise hammer the coordinator for no reason.
+func (s *Supervisor) Run(ctx context.Context) {
+	const (
+		minBackoff = time.Second
+		maxBackoff = 2 * time.Minute
+		keepAlive  = 5 * time.Minute
+	)
+
+	backoff := minBackoff
+	for {
+		if err := s.join(ctx); err != nil {
+			log.Warnf("luanet join failed, retrying in %s: %s", backoff, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+			continue
+		}
+
+		backoff = minBackoff
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(keepAlive):
+		}
+	}
+}
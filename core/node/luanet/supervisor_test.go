@@ -0,0 +1,38 @@
+package luanet
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSupervisorRunStaysJoinedAfterSuccess(t *testing.T) {
+	var calls int
+	ctx, cancel := context.WithTimeout(context.Background(), 1200*time.Millisecond)
+	defer cancel()
+
+	NewSupervisor(func(ctx context.Context) error {
+		calls++
+		return nil
+	}).Run(ctx)
+
+	if calls != 1 {
+		t.Fatalf("expected a successful join to keep the node joined without re-joining, got %d calls", calls)
+	}
+}
+
+func TestSupervisorRunRetriesWithBackoffOnFailure(t *testing.T) {
+	var calls int
+	ctx, cancel := context.WithTimeout(context.Background(), 2500*time.Millisecond)
+	defer cancel()
+
+	NewSupervisor(func(ctx context.Context) error {
+		calls++
+		return errors.New("boom")
+	}).Run(ctx)
+
+	if calls < 2 {
+		t.Fatalf("expected join to be retried after failure, got %d calls", calls)
+	}
+}
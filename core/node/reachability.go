@@ -0,0 +1,170 @@
+package node
+
+import (
+	"context"
+	"sync"
+
+	"github.com/libp2p/go-libp2p/core/event"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/network"
+
+	"github.com/luanet/lua-proto/proto"
+)
+
+// ReachabilityMonitor watches libp2p identify/reachability events and
+// keeps a cached port-forwarding TestResult up to date, the same way
+// go-libp2p-kad-dht consumes identify events to decide routing-table
+// admission: reachability and address changes are signals to react to,
+// not something we need a human to trigger with `ipfs test`.
+type ReachabilityMonitor struct {
+	host host.Host
+
+	// PushUpdate, if set, is called with the freshly computed result
+	// after every re-test so a caller can forward it on the luanet
+	// control channel.
+	PushUpdate func(proto.TestResult)
+
+	mu     sync.RWMutex
+	result proto.TestResult
+
+	// retestMu serializes retest runs and collapses a burst of events
+	// into a single extra retest instead of queuing one per event: each
+	// probe takes multi-second HTTP round trips, and the event-consumer
+	// goroutine must stay free to keep draining the event bus
+	// subscription rather than blocking on retest directly.
+	retestMu      sync.Mutex
+	retestRunning bool
+	retestPending bool
+
+	cancel context.CancelFunc
+
+	// probe computes a fresh TestResult. It's a field rather than a
+	// direct call to ipv4Test/ipv6Test so tests can substitute an
+	// artificially slow fake to exercise scheduleRetest's collapsing
+	// behavior without hitting the real probe endpoints.
+	probe func() proto.TestResult
+}
+
+// NewReachabilityMonitor creates a monitor bound to h. Call Start to
+// begin watching events.
+func NewReachabilityMonitor(h host.Host) *ReachabilityMonitor {
+	return &ReachabilityMonitor{host: h, probe: defaultProbe}
+}
+
+func defaultProbe() proto.TestResult {
+	return proto.TestResult{
+		Ports: map[string]proto.IpTest{
+			"v4": ipv4Test(),
+			"v6": ipv6Test(),
+		},
+	}
+}
+
+// Start subscribes to h's event bus and runs until ctx is done. It
+// kicks off an initial test in the background so Result has something
+// to return soon after Start returns, without making the caller wait
+// on the probes themselves.
+func (m *ReachabilityMonitor) Start(ctx context.Context) error {
+	sub, err := m.host.EventBus().Subscribe([]interface{}{
+		new(event.EvtLocalReachabilityChanged),
+		new(event.EvtNATDeviceTypeChanged),
+		new(event.EvtLocalAddressesUpdated),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	m.cancel = cancel
+
+	m.scheduleRetest()
+
+	go func() {
+		defer sub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-sub.Out():
+				if !ok {
+					return
+				}
+				m.handleEvent(evt)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Close stops the monitor.
+func (m *ReachabilityMonitor) Close() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+func (m *ReachabilityMonitor) handleEvent(evt interface{}) {
+	switch e := evt.(type) {
+	case event.EvtLocalReachabilityChanged:
+		if e.Reachability == network.ReachabilityPrivate {
+			m.scheduleRetest()
+		}
+	case event.EvtNATDeviceTypeChanged:
+		m.scheduleRetest()
+	case event.EvtLocalAddressesUpdated:
+		m.scheduleRetest()
+	}
+}
+
+// scheduleRetest runs retest on its own goroutine so the event-consumer
+// goroutine in Start never blocks on a multi-second probe. If a retest
+// is already in flight, it just marks one more pending round instead of
+// stacking up a goroutine per event - a burst of address-change events
+// should collapse into at most one extra retest, not one each.
+func (m *ReachabilityMonitor) scheduleRetest() {
+	m.retestMu.Lock()
+	if m.retestRunning {
+		m.retestPending = true
+		m.retestMu.Unlock()
+		return
+	}
+	m.retestRunning = true
+	m.retestMu.Unlock()
+
+	go func() {
+		for {
+			m.retest()
+
+			m.retestMu.Lock()
+			if !m.retestPending {
+				m.retestRunning = false
+				m.retestMu.Unlock()
+				return
+			}
+			m.retestPending = false
+			m.retestMu.Unlock()
+		}
+	}()
+}
+
+// retest re-runs the port-forwarding probes, updates the cached
+// result, and notifies PushUpdate if set.
+func (m *ReachabilityMonitor) retest() {
+	result := m.probe()
+
+	m.mu.Lock()
+	m.result = result
+	m.mu.Unlock()
+
+	if m.PushUpdate != nil {
+		m.PushUpdate(result)
+	}
+}
+
+// Result returns the most recently computed TestResult.
+func (m *ReachabilityMonitor) Result() proto.TestResult {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.result
+}
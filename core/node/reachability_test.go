@@ -0,0 +1,52 @@
+package node
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luanet/lua-proto/proto"
+)
+
+func TestReachabilityMonitorCollapsesBurstIntoOneExtraRetest(t *testing.T) {
+	m := NewReachabilityMonitor(nil)
+
+	var calls int32
+	started := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+
+	m.probe = func() proto.TestResult {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			started <- struct{}{}
+			<-unblock
+		}
+		return proto.TestResult{}
+	}
+
+	m.scheduleRetest()
+	<-started // the first retest is now blocked inside probe
+
+	// A burst of events arriving while a retest is in flight should
+	// collapse into at most one extra retest, not one per event.
+	for i := 0; i < 5; i++ {
+		m.scheduleRetest()
+	}
+
+	close(unblock)
+
+	deadline := time.After(time.Second)
+	for atomic.LoadInt32(&calls) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("expected a second, collapsed retest to run; got %d calls", atomic.LoadInt32(&calls))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// Give any erroneous extra retests a chance to show up before
+	// asserting the final count.
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("expected exactly 2 retest calls (initial + one collapsed), got %d", got)
+	}
+}
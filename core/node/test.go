@@ -1,8 +1,8 @@
 package node
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"time"
 
@@ -10,39 +10,170 @@ import (
 	"github.com/showwin/speedtest-go/speedtest"
 )
 
-func NodeTest(testResult chan proto.TestResult) {
-	result := proto.TestResult{}
-	result.Ports = make(map[string]proto.IpTest)
+// tickInterval is how often a running download/upload leg reports
+// progress back on the channel NodeTest returns.
+const tickInterval = 500 * time.Millisecond
+
+// TestOptions configures a single NodeTest run.
+type TestOptions struct {
+	// MaxBytes, when greater than zero, switches speedtest-go into its
+	// low-traffic "saving" mode for both legs; zero leaves saving mode
+	// off. The value itself is not enforced as a byte limit, because
+	// speedtest-go's DownloadTest/UploadTest expose no byte cap. It
+	// matters on metered nodes where even a reduced-precision test
+	// beats burning the full data budget every time.
+	MaxBytes int64
+
+	// ServerIndex selects which ranked speedtest server to use (0 =
+	// nearest/first), so a user on an asymmetric link can pick a
+	// nearby server instead of always getting targets[0].
+	ServerIndex int
+}
+
+// NodeTest runs the luanet port-forwarding and speedtest checks,
+// streaming phase updates on the returned channel instead of blocking
+// until everything finishes and printing dots to stdout - the daemon
+// has no terminal to print to, and a caller may want to cancel a test
+// that's burning its metered bandwidth budget. The channel is closed
+// when the test finishes, whether that's after the Final update or
+// because ctx was canceled.
+func NodeTest(ctx context.Context, opts TestOptions) <-chan proto.TestProgress {
+	progress := make(chan proto.TestProgress)
+	go func() {
+		defer close(progress)
+		runTest(ctx, opts, progress)
+	}()
+	return progress
+}
+
+func runTest(ctx context.Context, opts TestOptions, progress chan<- proto.TestProgress) {
+	result := proto.TestResult{Ports: make(map[string]proto.IpTest)}
+
+	send := func(p proto.TestProgress) bool {
+		select {
+		case progress <- p:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
 	// port forwarding checking...
-	v4 := ipv4Test()
-	v6 := ipv6Test()
-	if !v4.IsOpen() && !v6.IsOpen() {
+	result.Ports["v4"] = ipv4Test()
+	result.Ports["v6"] = ipv6Test()
+	if !result.Ports["v4"].IsOpen() && !result.Ports["v6"].IsOpen() {
 		logger.Error("Your node's ports is not open to the internet.")
 	}
-
-	result.Ports["v4"] = v4
-	result.Ports["v6"] = v6
+	if !send(proto.TestProgress{Phase: proto.TestPhasePortCheck, Ports: result.Ports}) {
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
 
 	// speedtest
-	user, _ := speedtest.FetchUserInfo()
-	serverList, _ := speedtest.FetchServers(user)
-	targets, _ := serverList.FindServer([]int{})
-	for _, s := range targets {
-		if err := testDownload(s, false); err == nil {
-			result.Download = s.DLSpeed
-		}
+	user, err := speedtest.FetchUserInfo()
+	if err != nil {
+		logger.Warn("Failed to fetch speedtest user info: ", err)
+		send(proto.TestProgress{Phase: proto.TestPhaseFinal, Result: &result})
+		return
+	}
+
+	serverList, err := speedtest.FetchServers(user)
+	if err != nil {
+		logger.Warn("Failed to fetch speedtest servers: ", err)
+		send(proto.TestProgress{Phase: proto.TestPhaseFinal, Result: &result})
+		return
+	}
+
+	targets, err := serverList.FindServer([]int{})
+	if err != nil || len(targets) == 0 {
+		logger.Warn("No speedtest servers available.")
+		send(proto.TestProgress{Phase: proto.TestPhaseFinal, Result: &result})
+		return
+	}
+
+	index := opts.ServerIndex
+	if index < 0 || index >= len(targets) {
+		index = 0
+	}
+	server := targets[index]
+
+	if !send(proto.TestProgress{Phase: proto.TestPhaseServerSelect, Server: server.Host}) {
+		return
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	savingMode := opts.MaxBytes > 0
+
+	if runLeg(ctx, proto.TestPhaseDownload, progress, func() error {
+		return server.DownloadTest(savingMode)
+	}) {
+		result.Download = server.DLSpeed
+	}
 
-		if err := testUpload(s, false); err == nil {
-			result.Upload = s.ULSpeed
+	if ctx.Err() == nil {
+		if runLeg(ctx, proto.TestPhaseUpload, progress, func() error {
+			return server.UploadTest(savingMode)
+		}) {
+			result.Upload = server.ULSpeed
 		}
+	}
 
-		showSpeedResult(s)
-		break
+	if !server.CheckResultValid() {
+		logger.Warn("Speedtest result seems to be wrong, please run it again.")
 	}
 
-	testResult <- result
-	return
+	send(proto.TestProgress{Phase: proto.TestPhaseFinal, Result: &result})
+}
+
+// runLeg runs test (a blocking speedtest-go download/upload call) in
+// the background while emitting periodic phase progress with the
+// elapsed time. speedtest-go has no incremental byte counter on this
+// API, so BytesSoFar stays 0 here; ElapsedMs is the only granular
+// signal available until that dependency grows a streaming mode.
+// It returns true if test completed without error.
+func runLeg(ctx context.Context, phase proto.TestPhase, progress chan<- proto.TestProgress, test func() error) bool {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- test() }()
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			select {
+			case progress <- proto.TestProgress{
+				Phase:      phase,
+				BytesSoFar: 0,
+				ElapsedMs:  time.Since(start).Milliseconds(),
+			}:
+			case <-ctx.Done():
+			}
+			return err == nil
+		case <-ticker.C:
+			select {
+			case progress <- proto.TestProgress{
+				Phase:      phase,
+				BytesSoFar: 0,
+				ElapsedMs:  time.Since(start).Milliseconds(),
+			}:
+			case <-ctx.Done():
+				return false
+			}
+		case <-ctx.Done():
+			// The speedtest-go call has no context support, so the
+			// background goroutine above keeps running uncounted
+			// until it returns; we just stop waiting on it.
+			return false
+		}
+	}
 }
+
 func ipv4Test() proto.IpTest {
 	var cResp proto.IpTest
 	URL := "http://ip4.luanet.io"
@@ -94,50 +225,3 @@ func ipv6Test() proto.IpTest {
 
 	return cResp
 }
-
-func testDownload(server *speedtest.Server, savingMode bool) error {
-	quit := make(chan bool)
-	fmt.Printf("[Speedtest] Downloading: ")
-	go dots(quit)
-	err := server.DownloadTest(savingMode)
-	quit <- true
-	if err != nil {
-		return err
-	}
-	fmt.Println()
-	return err
-}
-
-func testUpload(server *speedtest.Server, savingMode bool) error {
-	quit := make(chan bool)
-	fmt.Printf("[Speedtest] Uploading: ")
-	go dots(quit)
-	err := server.UploadTest(savingMode)
-	quit <- true
-	if err != nil {
-		return err
-	}
-	fmt.Println()
-	return nil
-}
-
-func dots(quit chan bool) {
-	for {
-		select {
-		case <-quit:
-			return
-		default:
-			time.Sleep(time.Second)
-			fmt.Print(".")
-		}
-	}
-}
-
-func showSpeedResult(server *speedtest.Server) {
-	fmt.Printf("[Speedtest] Download Speed: %5.2f Mbit/s\n", server.DLSpeed)
-	fmt.Printf("[Speedtest] Upload Speed: %5.2f Mbit/s\n\n", server.ULSpeed)
-	valid := server.CheckResultValid()
-	if !valid {
-		fmt.Println("Warning: Result seems to be wrong. Please speedtest again.")
-	}
-}